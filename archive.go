@@ -0,0 +1,157 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+)
+
+// maxArchiveUploadBytes caps how much of an incoming PUT /containers/{id}/archive
+// body we'll buffer in memory, so a single large upload can't exhaust the host.
+const maxArchiveUploadBytes = 1 << 30 // 1 GiB
+
+// ContainerArchiveHandler handles GET/PUT /containers/{id}/archive, streaming tar
+// archives in and out of a running container via CopyFromContainer/CopyToContainer.
+func ContainerArchiveHandler(w http.ResponseWriter, r *http.Request, id string) {
+	path := r.URL.Query().Get("path")
+	if !filepath.IsAbs(path) {
+		http.Error(w, "path must be absolute", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		getContainerArchive(w, r, id, path)
+	case http.MethodPut:
+		putContainerArchive(w, r, id, path)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// getContainerArchive streams a tar archive of path out of the container, mirroring
+// Docker's GET /containers/{id}/archive response framing.
+func getContainerArchive(w http.ResponseWriter, r *http.Request, id, path string) {
+	reader, stat, err := dockerClient.CopyFromContainer(r.Context(), id, path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer reader.Close()
+
+	statJSON, err := json.Marshal(stat)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString(statJSON))
+	w.Header().Set("Content-Type", "application/x-tar")
+	io.Copy(w, reader)
+}
+
+// putContainerArchive extracts an uploaded tar archive into path inside the
+// container, after rejecting any entry whose symlink target would escape path.
+func putContainerArchive(w http.ResponseWriter, r *http.Request, id, path string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxArchiveUploadBytes)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	if err := checkTarForSymlinkEscape(bytes.NewReader(body), path); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	err = dockerClient.CopyToContainer(r.Context(), id, path, bytes.NewReader(body), types.CopyToContainerOptions{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := Response{Status: "success", Data: map[string]string{"id": id, "path": path}}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// checkTarForSymlinkEscape walks every entry of an uploaded tar archive and rejects
+// one whose extracted path, or whose symlink/hardlink target, would resolve outside
+// destRoot (the actual extraction destination), the same class of bug covered by
+// moby's symlink-escape test.
+func checkTarForSymlinkEscape(r io.Reader, destRoot string) error {
+	destRoot = filepath.Clean(destRoot)
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		entryPath := filepath.Join(destRoot, hdr.Name)
+		if err := assertWithinRoot(destRoot, entryPath); err != nil {
+			return fmt.Errorf("archive entry %q escapes target directory %q", hdr.Name, destRoot)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeSymlink:
+			// Docker's extractor resolves a symlink's target relative to the
+			// directory the symlink itself lives in.
+			target := hdr.Linkname
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(entryPath), target)
+			}
+			target = filepath.Clean(target)
+
+			if err := assertWithinRoot(destRoot, target); err != nil {
+				return fmt.Errorf("archive entry %q escapes target directory via symlink to %q", hdr.Name, hdr.Linkname)
+			}
+		case tar.TypeLink:
+			// Unlike symlinks, Docker's extractor (pkg/archive) resolves a
+			// hardlink's target relative to the extraction root, not the entry's
+			// own directory.
+			target := hdr.Linkname
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(destRoot, target)
+			}
+			target = filepath.Clean(target)
+
+			if err := assertWithinRoot(destRoot, target); err != nil {
+				return fmt.Errorf("archive entry %q escapes target directory via hardlink to %q", hdr.Name, hdr.Linkname)
+			}
+		}
+	}
+}
+
+// assertWithinRoot returns an error if candidate does not resolve to root itself or
+// somewhere underneath it.
+func assertWithinRoot(root, candidate string) error {
+	rel, err := filepath.Rel(root, candidate)
+	if err != nil {
+		return err
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("path %q escapes root %q", candidate, root)
+	}
+	return nil
+}
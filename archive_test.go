@@ -0,0 +1,62 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+)
+
+func buildTarWithSymlink(name, linkname string) []byte {
+	return buildTarWithEntry(name, tar.TypeSymlink, linkname)
+}
+
+func buildTarWithEntry(name string, typeflag byte, linkname string) []byte {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Typeflag: typeflag,
+		Linkname: linkname,
+		Mode:     0777,
+	})
+	tw.Close()
+	return buf.Bytes()
+}
+
+func TestCheckTarForSymlinkEscapeRejectsEscapingSymlink(t *testing.T) {
+	archive := buildTarWithSymlink("link", "../../../../etc")
+
+	err := checkTarForSymlinkEscape(bytes.NewReader(archive), "/target")
+	if err == nil {
+		t.Fatal("expected an error for a symlink escaping the target directory, got nil")
+	}
+}
+
+func TestCheckTarForSymlinkEscapeAllowsContainedSymlink(t *testing.T) {
+	archive := buildTarWithSymlink("link", "subdir/file")
+
+	err := checkTarForSymlinkEscape(bytes.NewReader(archive), "/target")
+	if err != nil {
+		t.Fatalf("expected a contained symlink to be allowed, got error: %s", err)
+	}
+}
+
+func TestCheckTarForSymlinkEscapeRejectsEscapingHardlink(t *testing.T) {
+	// Docker resolves a hardlink's target relative to the extraction root, so
+	// "subdir/evil" -> ".." resolves to destRoot itself's parent, not "subdir/..".
+	archive := buildTarWithEntry("subdir/evil", tar.TypeLink, "..")
+
+	err := checkTarForSymlinkEscape(bytes.NewReader(archive), "/target")
+	if err == nil {
+		t.Fatal("expected an error for a hardlink escaping the target directory, got nil")
+	}
+}
+
+func TestCheckTarForSymlinkEscapeAllowsContainedHardlink(t *testing.T) {
+	archive := buildTarWithEntry("subdir/alias", tar.TypeLink, "subdir/other")
+
+	err := checkTarForSymlinkEscape(bytes.NewReader(archive), "/target")
+	if err != nil {
+		t.Fatalf("expected a contained hardlink to be allowed, got error: %s", err)
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// allocatedResources records the resource footprint reserved for a single container,
+// in the same units accepted by StartContainerRequest (whole CPUs, MB, MB).
+type allocatedResources struct {
+	CPUs    int
+	Memory  int
+	Storage int
+}
+
+var (
+	allocationsMu sync.Mutex
+	allocations   = make(map[string]allocatedResources)
+)
+
+// tryReserve admits a request for `required` resources against the host totals
+// (cpuCount cores, memory/storage in bytes) and, if they fit once already-reserved
+// containers are accounted for, reserves them immediately under a pending token.
+// Checking and reserving happen under the same lock so two concurrent admissions
+// can't both pass the check before either reserves. The caller must eventually call
+// promoteReservation (on success) or releaseAllocation (on failure) with the token.
+func tryReserve(required allocatedResources, cpuCount int, memoryAvailable, storageAvailable uint64) (token string, ok bool) {
+	allocationsMu.Lock()
+	defer allocationsMu.Unlock()
+
+	var used allocatedResources
+	for _, r := range allocations {
+		used.CPUs += r.CPUs
+		used.Memory += r.Memory
+		used.Storage += r.Storage
+	}
+
+	if required.CPUs > cpuCount-used.CPUs {
+		return "", false
+	}
+
+	memoryRemaining := int64(memoryAvailable) - int64(used.Memory)*1024*1024
+	if int64(required.Memory)*1024*1024 > memoryRemaining {
+		return "", false
+	}
+
+	storageRemaining := int64(storageAvailable) - int64(used.Storage)*1024*1024
+	if int64(required.Storage)*1024*1024 > storageRemaining {
+		return "", false
+	}
+
+	token = newReservationToken()
+	allocations[token] = required
+	return token, true
+}
+
+// newReservationToken returns a random key used to hold a reservation before the
+// real container ID is known.
+func newReservationToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "pending-" + hex.EncodeToString(b)
+}
+
+// promoteReservation moves a pending reservation over to the real container ID once
+// the container has been created.
+func promoteReservation(token, containerID string) {
+	allocationsMu.Lock()
+	defer allocationsMu.Unlock()
+	if r, ok := allocations[token]; ok {
+		delete(allocations, token)
+		allocations[containerID] = r
+	}
+}
+
+// reserveAllocation records that key (a container ID or reservation token) now holds
+// the given resources.
+func reserveAllocation(key string, r allocatedResources) {
+	allocationsMu.Lock()
+	defer allocationsMu.Unlock()
+	allocations[key] = r
+}
+
+// releaseAllocation frees the resources held by key, a container ID or a reservation
+// token that didn't pan out.
+func releaseAllocation(key string) {
+	allocationsMu.Lock()
+	defer allocationsMu.Unlock()
+	delete(allocations, key)
+}
+
+// reconcileAllocations rebuilds the in-memory allocation table from the containers
+// Docker already knows about, so a process restart doesn't forget reservations held
+// by containers that are still running.
+func reconcileAllocations(ctx context.Context) error {
+	list, err := dockerClient.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("ancestor", baseImage)),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, c := range list {
+		info, err := dockerClient.ContainerInspect(ctx, c.ID)
+		if err != nil || info.HostConfig == nil {
+			continue
+		}
+
+		r := allocatedResources{
+			CPUs:   int(info.HostConfig.NanoCPUs / 1e9),
+			Memory: int(info.HostConfig.Memory / (1024 * 1024)),
+		}
+		if size, ok := info.HostConfig.StorageOpt["size"]; ok {
+			r.Storage = parseStorageOptSizeMB(size)
+		}
+		reserveAllocation(c.ID, r)
+	}
+	return nil
+}
+
+// parseStorageOptSizeMB parses a HostConfig.StorageOpt "size" value such as "512M"
+// back into whole megabytes, the unit createAndStartContainer wrote it in.
+func parseStorageOptSizeMB(size string) int {
+	n, _ := strconv.Atoi(strings.TrimSuffix(size, "M"))
+	return n
+}
+
+// ContainersHandler dispatches requests under /containers to the appropriate
+// lifecycle handler based on the HTTP method and the path segments following the ID,
+// mirroring the subset of the Docker compat API used by this service.
+func ContainersHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/containers"), "/")
+
+	if path == "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ListContainersHandler(w, r)
+		return
+	}
+
+	segments := strings.Split(path, "/")
+	id := segments[0]
+
+	if len(segments) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			InspectContainerHandler(w, r, id)
+		case http.MethodDelete:
+			RemoveContainerHandler(w, r, id)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	switch segments[1] {
+	case "stop":
+		StopContainerHandler(w, r, id)
+	case "restart":
+		RestartContainerHandler(w, r, id)
+	case "logs":
+		ContainerLogsHandler(w, r, id)
+	case "stats":
+		ContainerStatsHandler(w, r, id)
+	case "archive":
+		ContainerArchiveHandler(w, r, id)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// ListContainersHandler handles GET /containers.
+func ListContainersHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := dockerClient.ContainerList(r.Context(), types.ContainerListOptions{All: true})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := Response{Status: "success", Data: list}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// InspectContainerHandler handles GET /containers/{id}.
+func InspectContainerHandler(w http.ResponseWriter, r *http.Request, id string) {
+	info, err := dockerClient.ContainerInspect(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	resp := Response{Status: "success", Data: info}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// StopContainerHandler handles POST /containers/{id}/stop.
+func StopContainerHandler(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := dockerClient.ContainerStop(r.Context(), id, container.StopOptions{}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := Response{Status: "success", Data: map[string]string{"id": id}}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// RestartContainerHandler handles POST /containers/{id}/restart.
+func RestartContainerHandler(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := dockerClient.ContainerRestart(r.Context(), id, container.StopOptions{}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := Response{Status: "success", Data: map[string]string{"id": id}}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// RemoveContainerHandler handles DELETE /containers/{id}, honoring the `force` and `v`
+// query params and releasing the container's resource reservation on success.
+func RemoveContainerHandler(w http.ResponseWriter, r *http.Request, id string) {
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+	removeVolumes, _ := strconv.ParseBool(r.URL.Query().Get("v"))
+
+	err := dockerClient.ContainerRemove(r.Context(), id, types.ContainerRemoveOptions{
+		Force:         force,
+		RemoveVolumes: removeVolumes,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	releaseAllocation(id)
+
+	resp := Response{Status: "success", Data: map[string]string{"id": id}}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ContainerLogsHandler handles GET /containers/{id}/logs, honoring the `stdout`,
+// `stderr`, `follow`, `tail` and `since` query params and relaying the Docker API's
+// multiplexed log stream framing straight through to the caller.
+func ContainerLogsHandler(w http.ResponseWriter, r *http.Request, id string) {
+	q := r.URL.Query()
+	showStdout := q.Get("stdout") != "false"
+	showStderr := q.Get("stderr") != "false"
+	follow, _ := strconv.ParseBool(q.Get("follow"))
+
+	reader, err := dockerClient.ContainerLogs(r.Context(), id, types.ContainerLogsOptions{
+		ShowStdout: showStdout,
+		ShowStderr: showStderr,
+		Follow:     follow,
+		Tail:       q.Get("tail"),
+		Since:      q.Get("since"),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer reader.Close()
+
+	w.Header().Set("Content-Type", "application/vnd.docker.multiplexed-stream")
+	flusher, canFlush := w.(http.Flusher)
+
+	buf := make([]byte, 32*1024)
+	wroteAny := false
+	for {
+		n, err := reader.Read(buf)
+		if n > 0 {
+			w.Write(buf[:n])
+			wroteAny = true
+			if canFlush {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				if wroteAny {
+					fmt.Printf("container logs stream for %s ended early: %s\n", id, err)
+				} else {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+				}
+			}
+			return
+		}
+	}
+}
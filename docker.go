@@ -0,0 +1,194 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+)
+
+// baseImage is the tag given to the SSH-enabled image all tenant containers run from.
+const baseImage = "debian-ssh"
+
+// sshPort is the container-side port sshd listens on.
+const sshPort = "22/tcp"
+
+// dockerClient is the shared Docker Engine API client used by every handler.
+var dockerClient *client.Client
+
+// initDockerClient negotiates an API version against the local Docker daemon and
+// stashes the client in dockerClient for the handlers to use.
+func initDockerClient() error {
+	c, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return err
+	}
+	dockerClient = c
+	return nil
+}
+
+// dockerfileSource returns the Dockerfile used to build the base SSH-enabled image.
+// Key material is never baked into the image: /root/.ssh is created empty here and
+// the caller's authorized_keys is injected into each container at creation time by
+// injectAuthorizedKey.
+func dockerfileSource() string {
+	return `
+# Use the official Debian image as the base image
+FROM debian:latest
+
+# Install SSH server
+RUN apt-get update && \
+    apt-get install -y openssh-server && \
+    mkdir -p /var/run/sshd /root/.ssh && \
+    chmod 700 /root/.ssh && \
+    sed -i 's/#PermitRootLogin prohibit-password/PermitRootLogin prohibit-password/' /etc/ssh/sshd_config && \
+    sed -i 's/#PasswordAuthentication yes/PasswordAuthentication no/' /etc/ssh/sshd_config && \
+    echo 'PasswordAuthentication no' >> /etc/ssh/sshd_config && \
+    echo 'ClientAliveInterval 60' >> /etc/ssh/sshd_config && \
+    echo 'ClientAliveCountMax 5' >> /etc/ssh/sshd_config
+
+# Expose SSH port
+EXPOSE 22
+
+# Start SSH service
+CMD ["/usr/sbin/sshd", "-D"]
+`
+}
+
+// buildContextTar packages the Dockerfile into an in-memory tar archive suitable for
+// the Docker Engine API's ImageBuild call, avoiding a shared file on disk.
+func buildContextTar(dockerfile string) (*bytes.Buffer, error) {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+
+	hdr := &tar.Header{
+		Name: "Dockerfile",
+		Mode: 0600,
+		Size: int64(len(dockerfile)),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write([]byte(dockerfile)); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// buildDockerImage builds the base SSH image via the Docker Engine API and returns the
+// decoded build log lines so callers can surface structured build progress.
+func buildDockerImage(ctx context.Context) ([]string, error) {
+	buildCtx, err := buildContextTar(dockerfileSource())
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := dockerClient.ImageBuild(ctx, buildCtx, types.ImageBuildOptions{
+		Tags:       []string{baseImage},
+		Dockerfile: "Dockerfile",
+		Remove:     true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return readJSONMessageStream(resp.Body)
+}
+
+// readJSONMessageStream decodes a stream of Docker JSON progress messages, as returned
+// by ImageBuild and ImagePull, into plain lines for reporting back to the caller.
+func readJSONMessageStream(r io.Reader) ([]string, error) {
+	var lines []string
+	dec := json.NewDecoder(r)
+	for {
+		var msg struct {
+			Stream      string `json:"stream"`
+			Status      string `json:"status"`
+			Progress    string `json:"progress"`
+			ErrorDetail *struct {
+				Message string `json:"message"`
+			} `json:"errorDetail"`
+		}
+		if err := dec.Decode(&msg); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return lines, err
+		}
+		if msg.ErrorDetail != nil {
+			return lines, fmt.Errorf("docker build failed: %s", msg.ErrorDetail.Message)
+		}
+		switch {
+		case msg.Stream != "":
+			lines = append(lines, msg.Stream)
+		case msg.Status != "":
+			lines = append(lines, msg.Status+" "+msg.Progress)
+		}
+	}
+	return lines, nil
+}
+
+// createAndStartContainer creates a container with the requested resource limits and
+// port mapping against the Docker Engine API, injects the tenant's authorized_keys,
+// starts it, and returns its ID.
+func createAndStartContainer(ctx context.Context, req StartContainerRequest, cpus, memoryMB, storageMB int, authorizedKey string) (string, error) {
+	exposedPorts := nat.PortSet{nat.Port(sshPort): struct{}{}}
+	portBindings := nat.PortMap{
+		nat.Port(sshPort): []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: req.Port}},
+	}
+
+	config := &container.Config{
+		Image:        baseImage,
+		ExposedPorts: exposedPorts,
+	}
+
+	hostConfig := &container.HostConfig{
+		PortBindings: portBindings,
+		Resources: container.Resources{
+			NanoCPUs: int64(cpus) * 1e9,
+			Memory:   int64(memoryMB) * 1024 * 1024,
+		},
+		StorageOpt: map[string]string{
+			"size": fmt.Sprintf("%dM", storageMB),
+		},
+	}
+
+	created, err := dockerClient.ContainerCreate(ctx, config, hostConfig, &network.NetworkingConfig{}, nil, "")
+	if err != nil {
+		return "", err
+	}
+
+	if err := injectAuthorizedKey(ctx, created.ID, authorizedKey); err != nil {
+		removeOrphanedContainer(created.ID)
+		return "", err
+	}
+
+	if err := dockerClient.ContainerStart(ctx, created.ID, types.ContainerStartOptions{}); err != nil {
+		removeOrphanedContainer(created.ID)
+		return "", err
+	}
+
+	return created.ID, nil
+}
+
+// removeOrphanedContainer force-removes a container created by createAndStartContainer
+// after a later step in the same call failed, so it doesn't linger holding resources
+// the allocation tracker was never told about. Uses a fresh context since the
+// request's context may already be canceled by the time this runs.
+func removeOrphanedContainer(containerID string) {
+	if err := dockerClient.ContainerRemove(context.Background(), containerID, types.ContainerRemoveOptions{Force: true}); err != nil {
+		fmt.Printf("failed to clean up orphaned container %s: %s\n", containerID, err)
+	}
+}
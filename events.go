@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// EventsHandler handles GET /events, proxying Docker Engine events (container
+// create/start/die/destroy, image build/pull, ...) to the caller as a long-lived
+// chunked JSON stream, mirroring the compat events handler in podman/moby.
+func EventsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	filterArgs := filters.NewArgs()
+	if raw := q.Get("filters"); raw != "" {
+		var decoded map[string][]string
+		if err := json.Unmarshal([]byte(raw), &decoded); err != nil {
+			http.Error(w, "invalid filters: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		for key, values := range decoded {
+			for _, value := range values {
+				filterArgs.Add(key, value)
+			}
+		}
+	}
+
+	ctx := r.Context()
+	msgs, errs := dockerClient.Events(ctx, types.EventsOptions{
+		Since:   q.Get("since"),
+		Until:   q.Get("until"),
+		Filters: filterArgs,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	flusher, canFlush := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+
+	wroteAny := false
+	for {
+		select {
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+			if err := enc.Encode(Response{Status: "success", Data: msg}); err != nil {
+				return
+			}
+			wroteAny = true
+			if canFlush {
+				flusher.Flush()
+			}
+		case err := <-errs:
+			if err != nil && err != io.EOF {
+				if wroteAny {
+					fmt.Printf("events stream ended early: %s\n", err)
+				} else {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+				}
+			}
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
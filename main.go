@@ -1,15 +1,12 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"os"
-	"os/exec"
 	"strconv"
-	"text/template"
 
 	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/disk"
@@ -24,84 +21,11 @@ type Response struct {
 
 // Request structure for starting the Docker container
 type StartContainerRequest struct {
-	CPUs    string `json:"cpus"`
-	Memory  string `json:"memory"`
-	Storage string `json:"storage"`
-	Port    string `json:"port"`
-}
-
-// Create Dockerfile dynamically
-func createDockerfile() error {
-	dockerfileContent := `
-# Use the official Debian image as the base image
-FROM debian:latest
-
-# Install SSH server
-RUN apt-get update && \
-    apt-get install -y openssh-server && \
-    mkdir /var/run/sshd && \
-    echo 'root:password' | chpasswd && \
-    sed -i 's/PermitRootLogin prohibit-password/PermitRootLogin yes/' /etc/ssh/sshd_config && \
-    sed -i 's/#PasswordAuthentication yes/PasswordAuthentication yes/' /etc/ssh/sshd_config && \
-    echo 'ClientAliveInterval 60' >> /etc/ssh/sshd_config && \
-    echo 'ClientAliveCountMax 5' >> /etc/ssh/sshd_config
-
-# Expose SSH port
-EXPOSE 22
-
-# Start SSH service
-CMD ["/usr/sbin/sshd", "-D"]
-`
-	return ioutil.WriteFile("Dockerfile", []byte(dockerfileContent), 0644)
-}
-
-// Build Docker image
-func buildDockerImage() error {
-	cmd := exec.Command("docker", "build", "-t", "debian-ssh", ".")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
-}
-
-// Create Docker Compose file dynamically
-func createDockerComposeFile(data map[string]string) error {
-	composeTemplate := `
-version: '3.7'
-
-services:
-  debian-ssh:
-    image: debian-ssh
-    deploy:
-      resources:
-        limits:
-          cpus: "{{.CPUS}}"
-          memory: "{{.MEMORY}}M"
-    ports:
-      - "{{.PORT}}:22"
-    storage_opt:
-      size: "{{.STORAGE}}M"
-`
-	tmpl, err := template.New("docker-compose").Parse(composeTemplate)
-	if err != nil {
-		return err
-	}
-
-	var composeFile bytes.Buffer
-	if err := tmpl.Execute(&composeFile, data); err != nil {
-		return err
-	}
-
-	return ioutil.WriteFile("docker-compose.yml", composeFile.Bytes(), 0644)
-}
-
-// Run Docker Compose
-func runDockerCompose() (string, error) {
-	cmd := exec.Command("docker-compose", "up", "-d")
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &out
-	err := cmd.Run()
-	return out.String(), err
+	CPUs      string `json:"cpus"`
+	Memory    string `json:"memory"`
+	Storage   string `json:"storage"`
+	Port      string `json:"port"`
+	PublicKey string `json:"public_key"`
 }
 
 // StartContainerHandler handles starting a Docker container with specified resources
@@ -148,51 +72,52 @@ func StartContainerHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if requiredCPUs > cpuCount || uint64(requiredMemory*1024*1024) > virtMem.Available || uint64(requiredStorage*1024*1024) > diskUsage.Free {
+	required := allocatedResources{CPUs: requiredCPUs, Memory: requiredMemory, Storage: requiredStorage}
+	token, ok := tryReserve(required, cpuCount, virtMem.Available, diskUsage.Free)
+	if !ok {
 		http.Error(w, "Insufficient resources", http.StatusForbidden)
 		return
 	}
 
-	// Create Dockerfile
-	if err := createDockerfile(); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+	ctx := r.Context()
 
-	// Build Docker image
-	if err := buildDockerImage(); err != nil {
+	buildLog, err := buildDockerImage(ctx)
+	if err != nil {
+		releaseAllocation(token)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Generate Docker Compose file
-	data := map[string]string{
-		"CPUS":    req.CPUs,
-		"MEMORY":  req.Memory,
-		"STORAGE": req.Storage,
-		"PORT":    req.Port,
+	authorizedKey := req.PublicKey
+	var generatedPrivateKey string
+	if authorizedKey == "" {
+		authorizedKey, generatedPrivateKey, err = generateSSHKeyPair()
+		if err != nil {
+			releaseAllocation(token)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 	}
 
-	if err := createDockerComposeFile(data); err != nil {
+	containerID, err := createAndStartContainer(ctx, req, requiredCPUs, requiredMemory, requiredStorage, authorizedKey)
+	if err != nil {
+		releaseAllocation(token)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Run Docker Compose
-	output, err := runDockerCompose()
-	if err != nil {
-		http.Error(w, output, http.StatusInternalServerError)
-		return
-	}
+	promoteReservation(token, containerID)
 
-	resp := Response{
-		Status: "success",
-		Data: map[string]string{
-			"docker_compose_output": output,
-			"ssh_url":               "ssh root@localhost -p " + req.Port,
-			"password":              "password",
-		},
+	data := map[string]interface{}{
+		"container_id": containerID,
+		"build_log":    buildLog,
+		"ssh_url":      "ssh root@localhost -p " + req.Port,
 	}
+	if generatedPrivateKey != "" {
+		data["private_key"] = generatedPrivateKey
+	}
+
+	resp := Response{Status: "success", Data: data}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
@@ -246,10 +171,22 @@ func GetStorageHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func main() {
+	if err := initDockerClient(); err != nil {
+		fmt.Printf("Failed to initialize Docker client: %s\n", err)
+		os.Exit(1)
+	}
+
+	if err := reconcileAllocations(context.Background()); err != nil {
+		fmt.Printf("Failed to reconcile container allocations: %s\n", err)
+	}
+
 	http.HandleFunc("/ram", GetRAMHandler)
 	http.HandleFunc("/cpu", GetCPUCoresHandler)
 	http.HandleFunc("/storage", GetStorageHandler)
 	http.HandleFunc("/start-container", StartContainerHandler)
+	http.HandleFunc("/containers", ContainersHandler)
+	http.HandleFunc("/containers/", ContainersHandler)
+	http.HandleFunc("/events", EventsHandler)
 
 	port := ":8085"
 	fmt.Printf("Server is running on port %s\n", port)
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+
+	"github.com/docker/docker/api/types"
+	"golang.org/x/crypto/ssh"
+)
+
+// generateSSHKeyPair creates a fresh ed25519 keypair for a tenant that did not supply
+// their own public key. It returns the key in OpenSSH authorized_keys format and the
+// PEM-encoded private key; the caller must return the private key to the client
+// exactly once and never persist it.
+func generateSSHKeyPair() (authorizedKey string, privateKeyPEM string, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return "", "", err
+	}
+
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		return "", "", err
+	}
+
+	return string(ssh.MarshalAuthorizedKey(sshPub)), string(pem.EncodeToMemory(block)), nil
+}
+
+// injectAuthorizedKey writes the given public key into /root/.ssh/authorized_keys
+// inside the (not yet started) container via the Docker Engine API, so no key
+// material is ever baked into the shared base image.
+func injectAuthorizedKey(ctx context.Context, containerID, authorizedKey string) error {
+	content := []byte(authorizedKey + "\n")
+
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	if err := tw.WriteHeader(&tar.Header{
+		Name: "authorized_keys",
+		Mode: 0600,
+		Size: int64(len(content)),
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(content); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return dockerClient.CopyToContainer(ctx, containerID, "/root/.ssh", buf, types.CopyToContainerOptions{})
+}
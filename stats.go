@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+)
+
+// containerStatsFrame is the shape written to callers for each sample, trimming the
+// raw Docker stats payload down to what the frontend actually renders.
+type containerStatsFrame struct {
+	CPUPercent  float64 `json:"cpu_percent"`
+	MemoryUsage uint64  `json:"memory_usage"`
+	MemoryLimit uint64  `json:"memory_limit"`
+	NetworkRx   uint64  `json:"network_rx"`
+	NetworkTx   uint64  `json:"network_tx"`
+	BlockRead   uint64  `json:"block_read"`
+	BlockWrite  uint64  `json:"block_write"`
+}
+
+// buildStatsFrame derives CPU percent and sums the per-interface/per-device counters
+// in a raw Docker stats sample, the same way the Docker CLI computes `docker stats`.
+func buildStatsFrame(v *types.StatsJSON) containerStatsFrame {
+	rx, tx := sumNetworkIO(v)
+	read, write := sumBlockIO(v)
+
+	return containerStatsFrame{
+		CPUPercent:  calcCPUPercent(v),
+		MemoryUsage: v.MemoryStats.Usage,
+		MemoryLimit: v.MemoryStats.Limit,
+		NetworkRx:   rx,
+		NetworkTx:   tx,
+		BlockRead:   read,
+		BlockWrite:  write,
+	}
+}
+
+// calcCPUPercent mirrors the Docker CLI's calculation: the fraction of total CPU time
+// the container consumed since the previous sample, scaled by the number of CPUs.
+func calcCPUPercent(v *types.StatsJSON) float64 {
+	cpuDelta := float64(v.CPUStats.CPUUsage.TotalUsage) - float64(v.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(v.CPUStats.SystemUsage) - float64(v.PreCPUStats.SystemUsage)
+	if systemDelta <= 0 || cpuDelta <= 0 {
+		return 0
+	}
+	return (cpuDelta / systemDelta) * float64(len(v.CPUStats.CPUUsage.PercpuUsage)) * 100.0
+}
+
+// sumNetworkIO adds up received/transmitted bytes across every network interface.
+func sumNetworkIO(v *types.StatsJSON) (rx, tx uint64) {
+	for _, n := range v.Networks {
+		rx += n.RxBytes
+		tx += n.TxBytes
+	}
+	return rx, tx
+}
+
+// sumBlockIO adds up block device read/write bytes from the recursive blkio entries.
+func sumBlockIO(v *types.StatsJSON) (read, write uint64) {
+	for _, entry := range v.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			read += entry.Value
+		case "write":
+			write += entry.Value
+		}
+	}
+	return read, write
+}
+
+// ContainerStatsHandler handles GET /containers/{id}/stats. With stream=true it keeps
+// the connection open and writes a newline-delimited JSON frame per sample, flushing
+// after each one; otherwise it decodes and returns a single snapshot.
+func ContainerStatsHandler(w http.ResponseWriter, r *http.Request, id string) {
+	stream := r.URL.Query().Get("stream") == "true"
+
+	statsResp, err := dockerClient.ContainerStats(r.Context(), id, stream)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer statsResp.Body.Close()
+
+	dec := json.NewDecoder(statsResp.Body)
+
+	if !stream {
+		var v types.StatsJSON
+		if err := dec.Decode(&v); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		resp := Response{Status: "success", Data: buildStatsFrame(&v)}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, canFlush := w.(http.Flusher)
+
+	wroteAny := false
+	for {
+		var v types.StatsJSON
+		if err := dec.Decode(&v); err != nil {
+			if err != io.EOF {
+				if wroteAny {
+					fmt.Printf("container stats stream for %s ended early: %s\n", id, err)
+				} else {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+				}
+			}
+			return
+		}
+
+		if err := json.NewEncoder(w).Encode(buildStatsFrame(&v)); err != nil {
+			return
+		}
+		wroteAny = true
+		if canFlush {
+			flusher.Flush()
+		}
+	}
+}